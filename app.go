@@ -0,0 +1,231 @@
+package webapp
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+)
+
+// ErrorEncoder writes the response for a handler that returned (or
+// panicked into) an error, following the go-kit ErrorEncoder pattern. It
+// is responsible for the status code, headers and body.
+type ErrorEncoder func(ctx context.Context, err error, w http.ResponseWriter)
+
+// ResponseEncoder writes the response for a handler that returned a
+// non-error value.
+type ResponseEncoder func(ctx context.Context, w http.ResponseWriter, resp interface{}) error
+
+// Config carries the hooks a WebApp uses to turn a handler's return value
+// into an HTTP response. A zero-valued field falls back to the package's
+// default behavior (DefaultErrorEncoder / DefaultResponseEncoder).
+type Config struct {
+	ErrorEncoder    ErrorEncoder
+	ResponseEncoder ResponseEncoder
+}
+
+// WebApp ties a Config to the HandleFunc/EncodeResponse machinery, letting
+// callers plug in custom error envelopes (e.g. an {ok, result, error}
+// shape) or encodings without patching this package.
+type WebApp struct {
+	cfg Config
+}
+
+// New returns a WebApp configured by cfg.
+func New(cfg Config) *WebApp {
+	if cfg.ErrorEncoder == nil {
+		cfg.ErrorEncoder = DefaultErrorEncoder
+	}
+	if cfg.ResponseEncoder == nil {
+		cfg.ResponseEncoder = DefaultResponseEncoder
+	}
+	return &WebApp{cfg: cfg}
+}
+
+// DefaultWebApp is the WebApp used by the package-level EncodeResponse and
+// HandleFunc functions.
+var DefaultWebApp = New(Config{})
+
+// HandleFunc converts handler into an http.HandlerFunc, getting the
+// response from it and encoding it through app's Config.
+func (app *WebApp) HandleFunc(handler Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		respData := handler.ServeWebApp(w, req)
+		app.EncodeResponse(w, req, respData)
+	}
+}
+
+// EncodeResponse encodes respData, dispatching to app's ErrorEncoder or
+// ResponseEncoder depending on whether respData is an error. Except for
+// StreamResponse, FileResponse and RedirectResponse — which write their own
+// response regardless of the client's Accept header — the codec used by
+// both hooks is negotiated here, against req's Accept header, and made
+// available to them through the context passed in. An error is always
+// reported to the client: content negotiation only ever falls back to
+// DefaultContentType for it, and never results in a 406, since otherwise
+// an unsatisfiable Accept header would silently swallow the real error.
+func (app *WebApp) EncodeResponse(w http.ResponseWriter, req *http.Request, respData interface{}) {
+	if respData == DoneResponse {
+		return
+	}
+
+	ctx := withRequest(req.Context(), req)
+
+	if err, ok := respData.(error); ok {
+		codec, contentType, ok := DefaultCodecs.Negotiate(req.Header.Get("Accept"))
+		if !ok {
+			codec, contentType = defaultCodec()
+		}
+		app.cfg.ErrorEncoder(withNegotiatedCodec(ctx, codec, contentType), err, w)
+		return
+	}
+
+	switch respData.(type) {
+	case StreamResponse, FileResponse, RedirectResponse:
+		// These bypass codec negotiation entirely.
+	default:
+		codec, contentType, ok := DefaultCodecs.Negotiate(req.Header.Get("Accept"))
+		if !ok {
+			w.WriteHeader(http.StatusNotAcceptable)
+			return
+		}
+		ctx = withNegotiatedCodec(ctx, codec, contentType)
+	}
+
+	if err := app.cfg.ResponseEncoder(ctx, w, respData); err != nil {
+		// ctx carries a negotiated codec unless respData is a
+		// StreamResponse, FileResponse or RedirectResponse (encodeFile
+		// returns an error when the file cannot be opened or stat'ed);
+		// for those, negotiatedCodecFromContext falls back to
+		// DefaultContentType.
+		app.cfg.ErrorEncoder(ctx, Error{
+			Code:      http.StatusInternalServerError,
+			OrigError: err,
+			ErrorID:   "RESPONSEMARSHALERROR",
+		}, w)
+	}
+}
+
+// DefaultResponseEncoder encodes a non-error response using the codec
+// negotiated by EncodeResponse, honoring the status code of a Response and
+// defaulting to 200 OK otherwise.
+func DefaultResponseEncoder(ctx context.Context, w http.ResponseWriter, respData interface{}) error {
+	var (
+		payload interface{}
+		code    int
+	)
+
+	switch resp := respData.(type) {
+	case Response:
+		payload = resp.Payload
+		code = resp.Code
+	case StreamResponse:
+		return encodeStream(w, resp)
+	case FileResponse:
+		return encodeFile(w, requestFromContext(ctx), resp)
+	case RedirectResponse:
+		return encodeRedirect(w, requestFromContext(ctx), resp)
+	default:
+		payload = respData
+		code = http.StatusOK
+	}
+
+	codec, contentType := negotiatedCodecFromContext(ctx)
+
+	var buf bytes.Buffer
+	if err := codec.Encode(&buf, payload); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(code)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// DefaultErrorEncoder normalizes err into an Error (following the same
+// rules EncodeResponse historically applied) and encodes it using the
+// codec negotiated by EncodeResponse.
+func DefaultErrorEncoder(ctx context.Context, err error, w http.ResponseWriter) {
+	webErr := asError(err)
+	codec, contentType := negotiatedCodecFromContext(ctx)
+
+	var buf bytes.Buffer
+	if encErr := codec.Encode(&buf, webErr); encErr != nil {
+		w.Header().Set("Content-Type", DefaultContentType)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"Code": 500, "ErrorId": "RESPONSEMARSHALERROR"}`))
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(webErr.Code)
+	w.Write(buf.Bytes())
+}
+
+// asError normalizes any error value into the package's own Error type.
+func asError(err error) Error {
+	switch e := err.(type) {
+	case Error:
+		return e
+	case ErrorIntf:
+		code, id := e.WebAppError()
+		return Error{
+			Code:      code,
+			OrigError: e,
+			ErrorID:   id,
+		}
+	default:
+		return Error{
+			Code:      http.StatusInternalServerError,
+			OrigError: err,
+			ErrorID:   "NONWEBAPPERROR",
+		}
+	}
+}
+
+// requestCtxKey is the context key EncodeResponse uses to pass the
+// original *http.Request down to the configured ResponseEncoder, for
+// response types (FileResponse, RedirectResponse) that need it.
+type requestCtxKey struct{}
+
+func withRequest(ctx context.Context, req *http.Request) context.Context {
+	return context.WithValue(ctx, requestCtxKey{}, req)
+}
+
+// requestFromContext returns the *http.Request stashed by EncodeResponse.
+func requestFromContext(ctx context.Context) *http.Request {
+	req, _ := ctx.Value(requestCtxKey{}).(*http.Request)
+	return req
+}
+
+// negotiatedCodecCtxKey is the context key EncodeResponse uses to pass the
+// codec it negotiated down to the configured ErrorEncoder/ResponseEncoder.
+type negotiatedCodecCtxKey struct{}
+
+type negotiatedCodec struct {
+	codec       Codec
+	contentType string
+}
+
+func withNegotiatedCodec(ctx context.Context, codec Codec, contentType string) context.Context {
+	return context.WithValue(ctx, negotiatedCodecCtxKey{}, negotiatedCodec{codec, contentType})
+}
+
+// negotiatedCodecFromContext returns the codec stashed by EncodeResponse,
+// falling back to defaultCodec when called outside of it.
+func negotiatedCodecFromContext(ctx context.Context) (Codec, string) {
+	if nc, ok := ctx.Value(negotiatedCodecCtxKey{}).(negotiatedCodec); ok {
+		return nc.codec, nc.contentType
+	}
+	return defaultCodec()
+}
+
+// defaultCodec returns the codec registered for DefaultContentType, used
+// as a fallback whenever Accept negotiation must not fail outright (e.g.
+// encoding an error response, so a bad Accept header can't swallow it).
+func defaultCodec() (Codec, string) {
+	if codec, ok := DefaultCodecs.Lookup(DefaultContentType); ok {
+		return codec, DefaultContentType
+	}
+	return jsonCodec{}, DefaultContentType
+}
@@ -0,0 +1,233 @@
+package webapp
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Codec knows how to decode and encode values for one or more MIME types.
+// Implementations are registered with a CodecRegistry so that DecodeRequest
+// and EncodeResponse can pick the right one based on the Content-Type and
+// Accept headers of a request.
+type Codec interface {
+	// Decode reads the encoded representation from r into v.
+	Decode(r io.Reader, v interface{}) error
+
+	// Encode writes the encoded representation of v to w.
+	Encode(w io.Writer, v interface{}) error
+
+	// ContentTypes returns the MIME types this codec can handle.
+	ContentTypes() []string
+}
+
+// CodecRegistry maps MIME types to the Codec responsible for them.
+type CodecRegistry struct {
+	codecs map[string]Codec
+}
+
+// NewCodecRegistry returns an empty CodecRegistry.
+func NewCodecRegistry() *CodecRegistry {
+	return &CodecRegistry{codecs: make(map[string]Codec)}
+}
+
+// RegisterCodec associates codec with every MIME type it declares,
+// overriding any codec previously registered for those types.
+func (reg *CodecRegistry) RegisterCodec(codec Codec) {
+	for _, ct := range codec.ContentTypes() {
+		reg.codecs[ct] = codec
+	}
+}
+
+// Lookup returns the codec registered for contentType, if any.
+func (reg *CodecRegistry) Lookup(contentType string) (Codec, bool) {
+	// Strip parameters such as ";charset=utf-8" before matching.
+	if idx := strings.IndexByte(contentType, ';'); idx >= 0 {
+		contentType = contentType[:idx]
+	}
+	codec, ok := reg.codecs[strings.TrimSpace(contentType)]
+	return codec, ok
+}
+
+// acceptEntry is a single, parsed member of an Accept header.
+type acceptEntry struct {
+	contentType string
+	q           float64
+}
+
+// parseAccept parses an Accept header into its entries, in q-value
+// descending order (ties broken by original order, as recommended by the
+// HTTP spec). Missing or malformed q-values default to 1.0.
+func parseAccept(accept string) []acceptEntry {
+	parts := strings.Split(accept, ",")
+	entries := make([]acceptEntry, 0, len(parts))
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		q := 1.0
+		contentType := part
+		if idx := strings.IndexByte(part, ';'); idx >= 0 {
+			contentType = strings.TrimSpace(part[:idx])
+			for _, param := range strings.Split(part[idx+1:], ";") {
+				param = strings.TrimSpace(param)
+				if strings.HasPrefix(param, "q=") {
+					if parsed, err := strconv.ParseFloat(param[2:], 64); err == nil {
+						q = parsed
+					}
+				}
+			}
+		}
+
+		entries = append(entries, acceptEntry{contentType: contentType, q: q})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].q > entries[j].q
+	})
+
+	return entries
+}
+
+// Negotiate picks the best codec registered in reg for the given Accept
+// header, honoring q-values as well as "type/*" and "*/*" wildcards. It
+// returns the matched content type alongside the codec.
+func (reg *CodecRegistry) Negotiate(accept string) (Codec, string, bool) {
+	if accept == "" {
+		accept = "*/*"
+	}
+
+	for _, entry := range parseAccept(accept) {
+		if entry.q <= 0 {
+			continue
+		}
+
+		if entry.contentType == "*/*" {
+			if ct, codec, ok := reg.any(); ok {
+				return codec, ct, true
+			}
+			continue
+		}
+
+		if strings.HasSuffix(entry.contentType, "/*") {
+			prefix := strings.TrimSuffix(entry.contentType, "*")
+			if ct, codec, ok := reg.anyWithPrefix(prefix); ok {
+				return codec, ct, true
+			}
+			continue
+		}
+
+		if codec, ok := reg.Lookup(entry.contentType); ok {
+			return codec, entry.contentType, true
+		}
+	}
+
+	return nil, "", false
+}
+
+// any returns an arbitrary registered content type, preferring
+// DefaultContentType when it is registered.
+func (reg *CodecRegistry) any() (string, Codec, bool) {
+	if codec, ok := reg.Lookup(DefaultContentType); ok {
+		return DefaultContentType, codec, true
+	}
+	for ct, codec := range reg.codecs {
+		return ct, codec, true
+	}
+	return "", nil, false
+}
+
+// anyWithPrefix returns a registered content type starting with prefix
+// (e.g. "application/"), preferring DefaultContentType when it matches.
+func (reg *CodecRegistry) anyWithPrefix(prefix string) (string, Codec, bool) {
+	if strings.HasPrefix(DefaultContentType, prefix) {
+		if codec, ok := reg.Lookup(DefaultContentType); ok {
+			return DefaultContentType, codec, true
+		}
+	}
+	for ct, codec := range reg.codecs {
+		if strings.HasPrefix(ct, prefix) {
+			return ct, codec, true
+		}
+	}
+	return "", nil, false
+}
+
+// DefaultContentType is used by Negotiate when a wildcard Accept entry
+// matches and as the Content-Type DecodeRequest assumes when a request
+// carries none. It may be reassigned to change the package-wide default.
+var DefaultContentType = "application/json"
+
+// DefaultCodecs is the CodecRegistry used by DecodeRequest and
+// EncodeResponse. It comes pre-populated with JSON, XML and form codecs;
+// call RegisterCodec to plug in additional formats such as MessagePack or
+// protobuf without forking the package.
+var DefaultCodecs = NewCodecRegistry()
+
+// RegisterCodec adds codec to DefaultCodecs, associating it with every
+// MIME type it declares.
+func RegisterCodec(codec Codec) {
+	DefaultCodecs.RegisterCodec(codec)
+}
+
+func init() {
+	RegisterCodec(jsonCodec{})
+	RegisterCodec(xmlCodec{})
+	RegisterCodec(formCodec{})
+}
+
+// jsonCodec implements Codec for application/json.
+type jsonCodec struct{}
+
+func (jsonCodec) Decode(r io.Reader, v interface{}) error { return json.NewDecoder(r).Decode(v) }
+func (jsonCodec) Encode(w io.Writer, v interface{}) error { return json.NewEncoder(w).Encode(v) }
+func (jsonCodec) ContentTypes() []string                  { return []string{"application/json"} }
+
+// xmlCodec implements Codec for application/xml.
+type xmlCodec struct{}
+
+func (xmlCodec) Decode(r io.Reader, v interface{}) error { return xml.NewDecoder(r).Decode(v) }
+func (xmlCodec) Encode(w io.Writer, v interface{}) error { return xml.NewEncoder(w).Encode(v) }
+func (xmlCodec) ContentTypes() []string                  { return []string{"application/xml", "text/xml"} }
+
+// formCodec implements Codec for application/x-www-form-urlencoded. It only
+// supports decoding into a *url.Values and encoding a url.Values, since form
+// encoding has no general mapping to arbitrary structs.
+type formCodec struct{}
+
+func (formCodec) Decode(r io.Reader, v interface{}) error {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return err
+	}
+
+	dst, ok := v.(*url.Values)
+	if !ok {
+		return errors.New("webapp: form codec can only decode into a *url.Values")
+	}
+	*dst = values
+	return nil
+}
+
+func (formCodec) Encode(w io.Writer, v interface{}) error {
+	values, ok := v.(url.Values)
+	if !ok {
+		return errors.New("webapp: form codec can only encode a url.Values")
+	}
+	_, err := io.WriteString(w, values.Encode())
+	return err
+}
+
+func (formCodec) ContentTypes() []string { return []string{"application/x-www-form-urlencoded"} }
@@ -0,0 +1,83 @@
+package webapp
+
+import (
+	"fmt"
+	"net/http"
+
+	validator "gopkg.in/go-playground/validator.v9"
+)
+
+// FieldError describes a single field-level validation failure, as
+// produced by a Validatable.Validate() implementation backed by
+// validator.v9.
+type FieldError struct {
+	// Field is the name of the struct field that failed validation.
+	Field string
+
+	// Rule is the validation tag that was violated, e.g. "required" or
+	// "email".
+	Rule string
+
+	// Message is a human-readable description of the failure.
+	Message string
+}
+
+// fieldErrorsFromValidator converts a validator.v9 ValidationErrors into
+// the package's own FieldError taxonomy.
+func fieldErrorsFromValidator(verrs validator.ValidationErrors) []FieldError {
+	fieldErrs := make([]FieldError, 0, len(verrs))
+	for _, fe := range verrs {
+		fieldErrs = append(fieldErrs, FieldError{
+			Field:   fe.Field(),
+			Rule:    fe.Tag(),
+			Message: fmt.Sprintf("failed on rule %q (param %q)", fe.Tag(), fe.Param()),
+		})
+	}
+	return fieldErrs
+}
+
+// NewValidationError returns a 400 Error carrying one FieldError per
+// validation failure in fieldErrors.
+func NewValidationError(fieldErrors []FieldError) Error {
+	return Error{
+		Code:    http.StatusBadRequest,
+		ErrorID: "VALIDATIONERROR",
+		Errors:  fieldErrors,
+	}
+}
+
+// NewNotFoundError returns an error with code of http.StatusNotFound. Meant
+// as a shortcut helper function.
+func NewNotFoundError(errorID string) Error {
+	return Error{
+		Code:    http.StatusNotFound,
+		ErrorID: errorID,
+	}
+}
+
+// NewForbiddenError returns an error with code of http.StatusForbidden.
+// Meant as a shortcut helper function.
+func NewForbiddenError(errorID string) Error {
+	return Error{
+		Code:    http.StatusForbidden,
+		ErrorID: errorID,
+	}
+}
+
+// NewConflictError returns an error with code of http.StatusConflict. Meant
+// as a shortcut helper function.
+func NewConflictError(errorID string) Error {
+	return Error{
+		Code:    http.StatusConflict,
+		ErrorID: errorID,
+	}
+}
+
+// NewUnauthorizedError returns an error with code of http.StatusUnauthorized.
+// Meant as a shortcut helper function.
+func NewUnauthorizedError(errorID string) Error {
+	return Error{
+		Code:    http.StatusUnauthorized,
+		ErrorID: errorID,
+	}
+}
@@ -0,0 +1,37 @@
+package webapp
+
+import (
+	"context"
+	"net/http"
+)
+
+// TypedHandlerFunc is a handler that receives a decoded, typed request and
+// returns a typed response, without touching http.ResponseWriter or
+// *http.Request directly.
+type TypedHandlerFunc[Req any, Resp any] func(ctx context.Context, req Req) (Resp, error)
+
+// Handle wraps fn into an http.HandlerFunc. The incoming request body is
+// decoded into a Req via DecodeRequest (which also runs Req.Validate, when
+// implemented), fn is invoked with the result, and the returned Resp or
+// error is encoded via EncodeResponse. This gives handlers compile-time
+// type safety around their request/response payloads while remaining a
+// plain http.HandlerFunc, so it can be registered on the same mux as
+// handlers built with HandleFunc. The result is not a webapp Handler:
+// HandleFunc, HandleHandler and Middleware do not accept it.
+func Handle[Req any, Resp any](fn TypedHandlerFunc[Req, Resp]) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var reqData Req
+		if err := DecodeRequest(req, &reqData); err != nil {
+			EncodeResponse(w, req, err)
+			return
+		}
+
+		respData, err := fn(req.Context(), reqData)
+		if err != nil {
+			EncodeResponse(w, req, err)
+			return
+		}
+
+		EncodeResponse(w, req, respData)
+	}
+}
@@ -0,0 +1,125 @@
+package webapp
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Middleware wraps a Handler to produce another Handler, letting request
+// processing be composed in layers around HandleHandler.
+type Middleware func(Handler) Handler
+
+// Chain combines mws into a single Middleware, applying them in the order
+// given: the first Middleware is outermost and sees the request first.
+func Chain(mws ...Middleware) Middleware {
+	return func(next Handler) Handler {
+		for i := len(mws) - 1; i >= 0; i-- {
+			next = mws[i](next)
+		}
+		return next
+	}
+}
+
+// RequestFunc runs before a Handler to enrich the request context, e.g.
+// with auth principals, request IDs or trace spans.
+type RequestFunc func(ctx context.Context, req *http.Request) context.Context
+
+// EnrichContext returns a Middleware that runs each RequestFunc, in order,
+// against the incoming request, and passes next a request carrying the
+// resulting context. Handlers can then retrieve the enriched values from
+// req.Context() without any change to the Handler signature.
+func EnrichContext(fns ...RequestFunc) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, req *http.Request) interface{} {
+			ctx := req.Context()
+			for _, fn := range fns {
+				ctx = fn(ctx, req)
+			}
+			return next.ServeWebApp(w, req.WithContext(ctx))
+		})
+	}
+}
+
+// Recover returns a Middleware that converts panics raised while serving
+// next into a 500 Error, encoded through the usual EncodeResponse path
+// instead of crashing the server.
+func Recover() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, req *http.Request) (resp interface{}) {
+			defer func() {
+				if r := recover(); r != nil {
+					resp = Error{
+						Code:      http.StatusInternalServerError,
+						ErrorID:   "PANIC",
+						OrigError: fmt.Errorf("%v", r),
+					}
+				}
+			}()
+			return next.ServeWebApp(w, req)
+		})
+	}
+}
+
+// Logger returns a Middleware that logs each request's method, path and
+// processing time to logger.
+func Logger(logger *log.Logger) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, req *http.Request) interface{} {
+			start := time.Now()
+			resp := next.ServeWebApp(w, req)
+			logger.Printf("%s %s (%s)", req.Method, req.URL.Path, time.Since(start))
+			return resp
+		})
+	}
+}
+
+// CORSConfig configures the CORS middleware.
+type CORSConfig struct {
+	// AllowedOrigins lists the origins allowed to make cross-origin
+	// requests. "*" allows any origin.
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+}
+
+// CORS returns a Middleware that sets the Access-Control-* response headers
+// described by cfg and responds to preflight OPTIONS requests directly,
+// short-circuiting the wrapped Handler.
+func CORS(cfg CORSConfig) Middleware {
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, req *http.Request) interface{} {
+			if origin := req.Header.Get("Origin"); origin != "" && originAllowed(cfg.AllowedOrigins, origin) {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				if methods != "" {
+					w.Header().Set("Access-Control-Allow-Methods", methods)
+				}
+				if headers != "" {
+					w.Header().Set("Access-Control-Allow-Headers", headers)
+				}
+			}
+
+			if req.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return DoneResponse
+			}
+
+			return next.ServeWebApp(w, req)
+		})
+	}
+}
+
+func originAllowed(origins []string, origin string) bool {
+	for _, allowed := range origins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package webapp
+
+import (
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// StreamResponse lets a handler return an arbitrary io.Reader to be
+// streamed to the client as-is, bypassing codec negotiation entirely.
+// Useful when marshaling the whole payload into memory first would be
+// wasteful, e.g. for large or unbounded bodies.
+type StreamResponse struct {
+	Reader      io.Reader
+	ContentType string
+	Code        int
+}
+
+// FileResponse lets a handler serve a file from disk via http.ServeContent,
+// which takes care of range requests and conditional GETs. When Attachment
+// is true, a Content-Disposition header is set prompting a download named
+// Name (or the file's own base name, when Name is empty).
+type FileResponse struct {
+	Path       string
+	Attachment bool
+	Name       string
+}
+
+// RedirectResponse issues an HTTP redirect to URL using Code, which
+// defaults to http.StatusFound when zero.
+type RedirectResponse struct {
+	URL  string
+	Code int
+}
+
+// encodeStream copies resp.Reader to w, setting resp.ContentType and
+// resp.Code beforehand. Once the status code is written the response has
+// started, so a copy failure can no longer be turned into an error
+// response: it is logged instead of returned.
+func encodeStream(w http.ResponseWriter, resp StreamResponse) error {
+	code := resp.Code
+	if code == 0 {
+		code = http.StatusOK
+	}
+
+	if resp.ContentType != "" {
+		w.Header().Set("Content-Type", resp.ContentType)
+	}
+	w.WriteHeader(code)
+
+	if _, err := io.Copy(w, resp.Reader); err != nil {
+		log.Printf("webapp: stream response interrupted: %v", err)
+	}
+	return nil
+}
+
+// encodeFile opens resp.Path and serves it through http.ServeContent,
+// setting Content-Disposition when resp.Attachment is set.
+func encodeFile(w http.ResponseWriter, req *http.Request, resp FileResponse) error {
+	f, err := os.Open(resp.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if resp.Attachment {
+		name := resp.Name
+		if name == "" {
+			name = filepath.Base(resp.Path)
+		}
+		w.Header().Set("Content-Disposition",
+			mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	}
+
+	http.ServeContent(w, req, info.Name(), info.ModTime(), f)
+	return nil
+}
+
+// encodeRedirect issues an HTTP redirect to resp.URL.
+func encodeRedirect(w http.ResponseWriter, req *http.Request, resp RedirectResponse) error {
+	code := resp.Code
+	if code == 0 {
+		code = http.StatusFound
+	}
+
+	http.Redirect(w, req, resp.URL, code)
+	return nil
+}
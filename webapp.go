@@ -1,9 +1,10 @@
 package webapp
 
-import (	
+import (
 	"fmt"
 	"net/http"
-	"encoding/json"	
+
+	validator "gopkg.in/go-playground/validator.v9"
 )
 
 // Error is the structure of a response that encodes a processing error
@@ -12,6 +13,11 @@ type Error struct {
 	ErrorID string
 	OrigError error
 	Extra interface{}
+
+	// Errors carries field-level validation failures, when the error
+	// originated from a failed Validatable.Validate() call. See
+	// NewValidationError.
+	Errors []FieldError `json:",omitempty"`
 }
 
 // Error matches the error interface and returns a simple string message.
@@ -60,95 +66,72 @@ type Validatable interface {
 }
 
 
-// DecodeRequest decodes an http request according to the Content-Type header
-// (right now only supports json)
+// DecodeRequest decodes an http request according to its Content-Type
+// header, using the codec registered for it in DefaultCodecs. A request
+// without a Content-Type header is decoded using DefaultContentType.
 func DecodeRequest(req *http.Request, reqData interface{}) error {
-	// TODO: Support more than just json depending on Content-Type header
-	
-	decoder := json.NewDecoder(req.Body)
-    defer req.Body.Close()
-    if err := decoder.Decode(reqData); err != nil {		
-        return Error{
+	contentType := req.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = DefaultContentType
+	}
+
+	codec, ok := DefaultCodecs.Lookup(contentType)
+	if !ok {
+		return Error{
+			Code:    http.StatusUnsupportedMediaType,
+			ErrorID: "UNSUPPORTEDCONTENTTYPE",
+		}
+	}
+
+	defer req.Body.Close()
+	if err := codec.Decode(req.Body, reqData); err != nil {
+		return Error{
 			Code: http.StatusBadRequest,
-			ErrorID: "INVALIDREQJSON",
+			ErrorID: "INVALIDREQBODY",
 			OrigError: err,
 		}
 	}
 
 	if validatable, ok := reqData.(Validatable) ; ok {
 		if err := validatable.Validate() ; err != nil {
+			if verrs, ok := err.(validator.ValidationErrors); ok {
+				return NewValidationError(fieldErrorsFromValidator(verrs))
+			}
 			return Error{
 				Code: http.StatusBadRequest,
 				ErrorID: "VALIDATIONERROR",
 				OrigError: err,
-			}			
+			}
 		}
 	}
-	
+
 	return nil
 }
 
-// EncodeResponse encodes a response object with the preferred encoding specified
-// on the Accepts http header. Right now supports only json.
+// EncodeResponse encodes a response object through DefaultWebApp, using the
+// encoding negotiated against the request's Accept header. It falls back to
+// DefaultContentType when the header is missing or permissive, and replies
+// with 406 Not Acceptable when none of the registered codecs satisfy it. An
+// error value never gets a 406: it is encoded with DefaultContentType
+// instead. StreamResponse, FileResponse and RedirectResponse skip
+// negotiation. To customize error envelopes or the success encoding,
+// construct a WebApp via New instead.
 func EncodeResponse(w http.ResponseWriter, req *http.Request, respData interface{}) {
-	var (
-		toMarshalData interface{}
-		responseCode int
-		marshalErr error
-		response []byte
-	)
-
-	if respData == DoneResponse { return }
-
-	switch resp:= respData.(type) {
-		case Response:
-			toMarshalData = resp.Payload
-			responseCode = resp.Code
-		case Error:
-			toMarshalData = resp
-			responseCode = resp.Code
-		case ErrorIntf:
-			code, id := resp.WebAppError()
-			responseCode = code
-			toMarshalData = Error{
-				Code: code,
-				OrigError: resp,
-				ErrorID: id,
-			}
-		case error:
-			// unhandled error
-			responseCode = http.StatusInternalServerError
-			toMarshalData = Error{
-				Code: responseCode,
-				OrigError: resp,
-				ErrorID: "NONWEBAPPERROR",
-			}
-		default:
-			toMarshalData = respData
-			responseCode = http.StatusOK
-	}
-
-	// TODO: Support more than just json depending on the Accepts Header
-	w.Header().Set("Content-Type", "application/json")
-
-	response, marshalErr = json.Marshal(toMarshalData)
-	if marshalErr != nil {
-		responseCode = http.StatusInternalServerError
-		response = []byte(`{"Code": 500, "ErrorId": "RESPONSEMARSHALERROR"}`)
-	}
-
-	w.WriteHeader(responseCode)
-	w.Write(response)
+	DefaultWebApp.EncodeResponse(w, req, respData)
 }
 
 // HandleFunc is a helper method that converts a webapp.HandlerFunc into an
-// http.HandlerFunc. This works as a middleware/filter, getting the response
-// from the WebApp function and encoding it to the client.
+// http.HandlerFunc via DefaultWebApp, getting the response from it and
+// encoding it to the client.
 func HandleFunc(handler HandlerFunc) http.HandlerFunc {
-	return func (w http.ResponseWriter, req *http.Request) {
-		respData := handler.ServeWebApp(w, req)
-		EncodeResponse(w, req, respData)
-	}
+	return DefaultWebApp.HandleFunc(handler)
+}
+
+// HandleHandler is the Handler-based counterpart to HandleFunc, for
+// Handler values built up via Middleware/Chain (panic recovery, logging,
+// CORS, etc.) rather than a bare HandlerFunc.
+func HandleHandler(handler Handler) http.HandlerFunc {
+	return DefaultWebApp.HandleFunc(handler)
 }
 
 // NewBadRequestError returns an error with code of http.StatusBadRequest. Meant